@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/sashabaranov/go-openai"
+)
+
+// splitIntoChunks splits s into chunks of at most chunkSize characters, with
+// consecutive chunks overlapping by overlap characters so a violation that
+// straddles a chunk boundary is still visible to at least one chunk pair.
+func splitIntoChunks(s string, chunkSize, overlap int) []string {
+	if chunkSize <= 0 || len(s) <= chunkSize {
+		return []string{s}
+	}
+	if overlap < 0 || overlap >= chunkSize {
+		overlap = 0
+	}
+
+	var chunks []string
+	step := chunkSize - overlap
+	for start := 0; start < len(s); start += step {
+		end := start + chunkSize
+		if end > len(s) {
+			end = len(s)
+		}
+		chunks = append(chunks, s[start:end])
+		if end == len(s) {
+			break
+		}
+	}
+	return chunks
+}
+
+// tokensToChars approximates an OpenAI token budget in characters, using the
+// common rule of thumb of ~4 characters per token.
+func tokensToChars(tokens int) int {
+	return tokens * 4
+}
+
+// shouldRetry reports whether err is a transient OpenAI/network failure
+// worth retrying. A timed-out net.Error and a 5xx/429 openai.APIError are
+// two different, mutually exclusive ways a request can fail - never both at
+// once - so they're checked independently rather than ANDed together.
+func shouldRetry(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.HTTPStatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	return false
+}
+
+// progressFunc reports how many of the total chunk-vs-chunk comparisons
+// have completed so far. It may be called concurrently.
+type progressFunc func(done, total int)
+
+// jsonObjectResponseFormat asks the model to return a JSON object rather
+// than free-form text, per OpenAI's response_format: json_object mode.
+var jsonObjectResponseFormat = &openai.ChatCompletionResponseFormat{
+	Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+}
+
+// completeChat sends a single prompt to the configured model, retrying
+// transient failures with exponential backoff.
+func (s *APIServer) completeChat(ctx context.Context, prompt string, responseFormat *openai.ChatCompletionResponseFormat) (string, error) {
+	var content string
+
+	operation := func() error {
+		resp, err := s.aiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model:     openai.GPT4,
+			MaxTokens: s.config.OpenAI.Tokens,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: prompt},
+			},
+			ResponseFormat: responseFormat,
+		})
+		if err != nil {
+			if shouldRetry(err) {
+				return err
+			}
+			return backoff.Permanent(err)
+		}
+		if len(resp.Choices) == 0 {
+			return backoff.Permanent(fmt.Errorf("completion returned no choices"))
+		}
+		content = resp.Choices[0].Message.Content
+		return nil
+	}
+
+	policy := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3), ctx)
+	if err := backoff.Retry(operation, policy); err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// completeChatFindings sends prompt in JSON mode and decodes the response
+// into a slice of Finding, validating it against findingsSchema.
+func (s *APIServer) completeChatFindings(ctx context.Context, prompt string) ([]Finding, error) {
+	raw, err := s.completeChat(ctx, prompt, jsonObjectResponseFormat)
+	if err != nil {
+		return nil, err
+	}
+	return parseFindings(raw)
+}
+
+// analyzeContent compares policy against webpage for compliance violations.
+// Both are split into overlapping, token-aware chunks; every policy chunk is
+// compared against every webpage chunk concurrently (bounded by
+// config.Analysis.Concurrency), and the resulting partial findings are
+// merged by a second "reduce" completion that de-duplicates and ranks them.
+// progress, if non-nil, is invoked after each chunk pair completes.
+func (s *APIServer) analyzeContent(ctx context.Context, policy, webpage []string, progress progressFunc) ([]Finding, error) {
+	chunkChars := tokensToChars(s.config.Analysis.ChunkTokens)
+	overlapChars := tokensToChars(s.config.Analysis.ChunkOverlapTokens)
+
+	policyChunks := splitIntoChunks(strings.Join(policy, "\n"), chunkChars, overlapChars)
+	webpageChunks := splitIntoChunks(strings.Join(webpage, "\n"), chunkChars, overlapChars)
+
+	type pair struct{ policyIdx, webpageIdx int }
+	pairs := make([]pair, 0, len(policyChunks)*len(webpageChunks))
+	for i := range policyChunks {
+		for j := range webpageChunks {
+			pairs = append(pairs, pair{i, j})
+		}
+	}
+
+	concurrency := s.config.Analysis.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]Finding, len(pairs))
+	errs := make([]error, len(pairs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var completed int32
+	var cancelOnce sync.Once
+
+	for i, p := range pairs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p pair) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				errs[i] = ctx.Err()
+				return
+			}
+
+			prompt := fmt.Sprintf(s.config.Prompt, webpageChunks[p.webpageIdx], policyChunks[p.policyIdx])
+			findings, err := s.completeChatFindings(ctx, prompt)
+			results[i], errs[i] = findings, err
+			if err != nil {
+				// Cancel immediately so sibling in-flight and not-yet-started
+				// OpenAI calls are aborted rather than left to run to
+				// completion before the error is even noticed.
+				cancelOnce.Do(cancel)
+			}
+
+			if progress != nil {
+				progress(int(atomic.AddInt32(&completed, 1)), len(pairs))
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	var partials []Finding
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("compare chunk %d/%d: %w", pairs[i].policyIdx, pairs[i].webpageIdx, err)
+		}
+		partials = append(partials, results[i]...)
+	}
+
+	reduced, err := s.reduceFindings(ctx, partials)
+	if err != nil {
+		return nil, fmt.Errorf("reduce findings: %w", err)
+	}
+
+	return reduced, nil
+}
+
+// reduceFindings merges the partial findings produced for each chunk pair
+// into a single de-duplicated, ranked list via one more model call.
+func (s *APIServer) reduceFindings(ctx context.Context, partials []Finding) ([]Finding, error) {
+	if len(partials) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(partials)
+	if err != nil {
+		return nil, fmt.Errorf("encode partial findings: %w", err)
+	}
+	prompt := fmt.Sprintf(s.config.Analysis.ReducePrompt, string(encoded))
+	return s.completeChatFindings(ctx, prompt)
+}
+
+// analyzeStream is the Server-Sent Events counterpart to analyze: it
+// streams a "progress" event after every chunk pair completes, followed by
+// a final "done" event carrying the findings (or an "error" event on
+// failure). It does not read or write the Storage cache.
+func (s *APIServer) analyzeStream(w http.ResponseWriter, r *http.Request) {
+	policy := r.URL.Query().Get("policy")
+	webpage := r.URL.Query().Get("webpage")
+	if policy == "" || webpage == "" {
+		http.Error(w, "Invalid input: policy is invalid", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	fetchOpts := FetchOptionsFromRequest(r)
+	policyContent, err := fetchContent(r.Context(), policy, fetchOpts)
+	if err != nil {
+		writeSSEEvent(w, flusher, "error", err.Error())
+		return
+	}
+	webpageContent, err := fetchContent(r.Context(), webpage, fetchOpts)
+	if err != nil {
+		writeSSEEvent(w, flusher, "error", err.Error())
+		return
+	}
+
+	var mu sync.Mutex
+	progress := func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		payload, _ := json.Marshal(map[string]int{"done": done, "total": total})
+		writeSSEEvent(w, flusher, "progress", string(payload))
+	}
+
+	findings, err := s.analyzeContent(r.Context(), policyContent, webpageContent, progress)
+	if err != nil {
+		writeSSEEvent(w, flusher, "error", err.Error())
+		return
+	}
+
+	payload, _ := json.Marshal(map[string][]Finding{"findings": findings})
+	writeSSEEvent(w, flusher, "done", string(payload))
+}
+
+func writeSSEEvent(w http.ResponseWriter, flusher http.Flusher, event, data string) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	flusher.Flush()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSplitIntoChunks(t *testing.T) {
+	got := splitIntoChunks("abcdefghij", 4, 1)
+	want := []string{"abcd", "defg", "ghij"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitIntoChunksNoOp(t *testing.T) {
+	got := splitIntoChunks("short", 100, 0)
+	if len(got) != 1 || got[0] != "short" {
+		t.Errorf("got %v, want [\"short\"]", got)
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestShouldRetry(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable}, true},
+		{"rate limited", &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests}, true},
+		{"client error", &openai.APIError{HTTPStatusCode: http.StatusBadRequest}, false},
+		{"network timeout", timeoutErr{}, true},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := shouldRetry(c.err); got != c.want {
+				t.Errorf("shouldRetry(%v) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+}
+
+var _ net.Error = timeoutErr{}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const ownerContextKey contextKey = "owner"
+
+// authMiddleware enforces a Bearer token on every request and, on success,
+// stashes the resolved owner in the request context so handlers can scope
+// Storage calls to it.
+func (s *APIServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		owner, err := s.store.Authenticate(token)
+		if err != nil {
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ownerContextKey, owner)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ownerFromContext returns the authenticated owner stashed by authMiddleware.
+func ownerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerContextKey).(string)
+	return owner
+}
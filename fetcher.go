@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+	"github.com/ledongthuc/pdf"
+)
+
+// ContentFetcher extracts a flat list of text blocks (headings, paragraphs,
+// list items, table rows, in document order) from whatever is at url.
+type ContentFetcher interface {
+	Fetch(ctx context.Context, rawURL string) ([]string, error)
+}
+
+// FetchOptions mirrors the ?mode=crawl&depth=N query parameters accepted by
+// /compliance and /compliance/stream.
+type FetchOptions struct {
+	Mode  string // "" (single page, default) or "crawl"
+	Depth int    // max sitemap crawl depth, used when Mode == "crawl"
+}
+
+// FetchOptionsFromRequest reads mode/depth off r's query string.
+func FetchOptionsFromRequest(r *http.Request) FetchOptions {
+	opts := FetchOptions{Mode: r.URL.Query().Get("mode"), Depth: 2}
+	if depth, err := strconv.Atoi(r.URL.Query().Get("depth")); err == nil && depth > 0 {
+		opts.Depth = depth
+	}
+	return opts
+}
+
+// fetchContent fetches rawURL, selecting a ContentFetcher by content-type
+// sniffing and URL heuristics, or crawling its sitemap when opts.Mode is
+// "crawl".
+func fetchContent(ctx context.Context, rawURL string, opts FetchOptions) ([]string, error) {
+	if opts.Mode == "crawl" {
+		crawler := &SitemapCrawler{Fetcher: &HTMLFetcher{}, MaxDepth: opts.Depth, MaxPages: 25}
+		return crawler.Fetch(ctx, rawURL)
+	}
+
+	fetcher, err := selectFetcher(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.Fetch(ctx, rawURL)
+}
+
+// selectFetcher sniffs rawURL's content-type (HEAD request) and falls back
+// to its file extension / host heuristics when that fails.
+func selectFetcher(ctx context.Context, rawURL string) (ContentFetcher, error) {
+	if strings.HasSuffix(strings.ToLower(rawURL), ".pdf") {
+		return &PDFFetcher{}, nil
+	}
+
+	if req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil); err == nil {
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			switch contentType := resp.Header.Get("Content-Type"); {
+			case strings.Contains(contentType, "application/pdf"):
+				return &PDFFetcher{}, nil
+			case strings.Contains(contentType, "text/html"):
+				if isLikelySPA(rawURL) {
+					return &HeadlessFetcher{}, nil
+				}
+				return &HTMLFetcher{}, nil
+			}
+		}
+	}
+
+	if isLikelySPA(rawURL) {
+		return &HeadlessFetcher{}, nil
+	}
+	return &HTMLFetcher{}, nil
+}
+
+// isLikelySPA flags hosts that are conventionally client-rendered
+// (app./my. subdomains), where a plain HTML fetch would return an empty
+// shell.
+func isLikelySPA(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return strings.HasPrefix(host, "app.") || strings.HasPrefix(host, "my.")
+}
+
+// HTMLFetcher fetches a single HTML page via an ordinary HTTP GET.
+type HTMLFetcher struct{}
+
+func (f *HTMLFetcher) Fetch(ctx context.Context, rawURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request for %s: %w", rawURL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("fetch %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", rawURL, err)
+	}
+	return extractBlocks(doc), nil
+}
+
+// extractBlocks walks doc in document order, collecting the unique text of
+// every heading, paragraph, list, and table.
+func extractBlocks(doc *goquery.Document) []string {
+	uniqueHeadings := make(map[string]bool)
+	uniqueParagraphs := make(map[string]bool)
+	uniqueLists := make(map[string]bool)
+	uniqueTables := make(map[string]bool)
+
+	var output []string
+
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "h2", "h3":
+			headingText := strings.TrimSpace(s.Text())
+			if _, exists := uniqueHeadings[headingText]; !exists {
+				output = append(output, headingText)
+				uniqueHeadings[headingText] = true
+			}
+		case "p":
+			paragraphText := strings.TrimSpace(s.Text())
+			if _, exists := uniqueParagraphs[paragraphText]; !exists {
+				output = append(output, paragraphText)
+				uniqueParagraphs[paragraphText] = true
+			}
+		case "ul":
+			listItems := s.Find("li").Map(func(i int, s *goquery.Selection) string {
+				return strings.TrimSpace(s.Text())
+			})
+			listText := strings.Join(listItems, "\n")
+			if _, exists := uniqueLists[listText]; !exists {
+				output = append(output, listText)
+				uniqueLists[listText] = true
+			}
+		case "table":
+			tableRows := s.Find("tr").Map(func(i int, s *goquery.Selection) string {
+				columns := s.Find("th, td").Map(func(i int, s *goquery.Selection) string {
+					return strings.TrimSpace(s.Text())
+				})
+				return strings.Join(columns, "\t")
+			})
+			tableText := strings.Join(tableRows, "\n")
+			if _, exists := uniqueTables[tableText]; !exists {
+				output = append(output, tableText)
+				uniqueTables[tableText] = true
+			}
+		}
+	})
+
+	return output
+}
+
+// PDFFetcher fetches a PDF document and extracts its text, one block per
+// paragraph (blank-line-separated run of text) per page.
+type PDFFetcher struct{}
+
+func (f *PDFFetcher) Fetch(ctx context.Context, rawURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request for %s: %w", rawURL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("fetch %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", rawURL, err)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("parse pdf %s: %w", rawURL, err)
+	}
+
+	var output []string
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("extract text from %s page %d: %w", rawURL, i, err)
+		}
+		for _, para := range strings.Split(text, "\n\n") {
+			if trimmed := strings.TrimSpace(para); trimmed != "" {
+				output = append(output, trimmed)
+			}
+		}
+	}
+	return output, nil
+}
+
+// HeadlessFetcher renders a page in a headless Chrome instance before
+// extracting its content, for JS-rendered SPAs that return an empty shell
+// to a plain HTTP GET.
+type HeadlessFetcher struct {
+	Timeout time.Duration
+}
+
+func (f *HeadlessFetcher) Fetch(ctx context.Context, rawURL string) ([]string, error) {
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, chromedp.DefaultExecAllocatorOptions[:]...)
+	defer cancelAlloc()
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+	browserCtx, cancelTimeout := context.WithTimeout(browserCtx, timeout)
+	defer cancelTimeout()
+
+	var html string
+	if err := chromedp.Run(browserCtx,
+		chromedp.Navigate(rawURL),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	); err != nil {
+		return nil, fmt.Errorf("render %s: %w", rawURL, err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("parse rendered %s: %w", rawURL, err)
+	}
+	return extractBlocks(doc), nil
+}
+
+// SitemapCrawler expands a single URL into every page listed in its
+// /sitemap.xml (following nested sitemap indexes up to MaxDepth), fetching
+// each one with Fetcher and concatenating their content.
+type SitemapCrawler struct {
+	Fetcher  ContentFetcher
+	MaxDepth int
+	MaxPages int
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+func (c *SitemapCrawler) Fetch(ctx context.Context, rawURL string) ([]string, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", rawURL, err)
+	}
+
+	depth := c.MaxDepth
+	if depth <= 0 {
+		depth = 1
+	}
+
+	pages, err := c.collectPages(ctx, fmt.Sprintf("%s://%s/sitemap.xml", base.Scheme, base.Host), depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		pages = []string{rawURL}
+	}
+
+	maxPages := c.MaxPages
+	if maxPages <= 0 || maxPages > len(pages) {
+		maxPages = len(pages)
+	}
+
+	var output []string
+	for _, page := range pages[:maxPages] {
+		content, err := c.Fetcher.Fetch(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("crawl %s: %w", page, err)
+		}
+		output = append(output, content...)
+	}
+	return output, nil
+}
+
+// collectPages resolves a sitemap URL to the list of pages it describes,
+// recursing into nested sitemap indexes up to depth levels deep.
+func (c *SitemapCrawler) collectPages(ctx context.Context, sitemapURL string, depth int) ([]string, error) {
+	if depth <= 0 {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request for %s: %w", sitemapURL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("fetch %s: unexpected status %d", sitemapURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", sitemapURL, err)
+	}
+
+	var index sitemapIndex
+	if err := xml.Unmarshal(body, &index); err == nil && len(index.Sitemaps) > 0 {
+		var pages []string
+		for _, entry := range index.Sitemaps {
+			nested, err := c.collectPages(ctx, entry.Loc, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, nested...)
+		}
+		return pages, nil
+	}
+
+	var urlSet sitemapURLSet
+	if err := xml.Unmarshal(body, &urlSet); err != nil {
+		return nil, fmt.Errorf("parse sitemap %s: %w", sitemapURL, err)
+	}
+	pages := make([]string, 0, len(urlSet.URLs))
+	for _, u := range urlSet.URLs {
+		pages = append(pages, u.Loc)
+	}
+	return pages, nil
+}
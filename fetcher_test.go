@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsLikelySPA(t *testing.T) {
+	cases := []struct {
+		url  string
+		want bool
+	}{
+		{"https://app.example.com/", true},
+		{"https://my.example.com/dashboard", true},
+		{"https://www.example.com/", false},
+		{"not a url", false},
+	}
+	for _, c := range cases {
+		if got := isLikelySPA(c.url); got != c.want {
+			t.Errorf("isLikelySPA(%q) = %v, want %v", c.url, got, c.want)
+		}
+	}
+}
+
+func TestHTMLFetcherReturnsRealErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	fetcher := &HTMLFetcher{}
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	if err == nil {
+		t.Fatal("expected an error for a 404 response, got nil")
+	}
+}
+
+func TestHTMLFetcherExtractsBlocks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><h2>Disclosures</h2><p>All deposits are FDIC insured.</p></body></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := &HTMLFetcher{}
+	got, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(got) != 2 || got[0] != "Disclosures" || got[1] != "All deposits are FDIC insured." {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestSitemapCrawlerExpandsURLs(t *testing.T) {
+	handler := http.NewServeMux()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	handler.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>page a</p></body></html>`))
+	})
+	handler.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>page b</p></body></html>`))
+	})
+	handler.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		body := strings.NewReplacer("PAGE_A", server.URL+"/a", "PAGE_B", server.URL+"/b").Replace(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>PAGE_A</loc></url>
+  <url><loc>PAGE_B</loc></url>
+</urlset>`)
+		w.Write([]byte(body))
+	})
+
+	crawler := &SitemapCrawler{Fetcher: &HTMLFetcher{}, MaxDepth: 1, MaxPages: 10}
+	pages, err := crawler.collectPages(context.Background(), server.URL+"/sitemap.xml", 1)
+	if err != nil {
+		t.Fatalf("collectPages: %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2: %v", len(pages), pages)
+	}
+
+	content, err := crawler.Fetch(context.Background(), server.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(content) != 2 {
+		t.Fatalf("got %d content blocks, want 2: %v", len(content), content)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Finding is one compliance violation, whether surfaced by the LLM or by
+// the RulesEngine.
+type Finding struct {
+	Rule           string  `json:"rule"`
+	Severity       string  `json:"severity"`
+	PolicyExcerpt  string  `json:"policy_excerpt"`
+	WebpageExcerpt string  `json:"webpage_excerpt"`
+	Confidence     float64 `json:"confidence"`
+	Suggestion     string  `json:"suggestion"`
+}
+
+// findingsSchema validates the JSON object an LLM completion must return
+// when asked for structured findings: {"findings": [...]}.
+const findingsSchema = `{
+	"type": "object",
+	"required": ["findings"],
+	"properties": {
+		"findings": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["rule", "severity", "policy_excerpt", "webpage_excerpt", "confidence", "suggestion"],
+				"properties": {
+					"rule": {"type": "string"},
+					"severity": {"type": "string", "enum": ["low", "medium", "high", "critical"]},
+					"policy_excerpt": {"type": "string"},
+					"webpage_excerpt": {"type": "string"},
+					"confidence": {"type": "number", "minimum": 0, "maximum": 1},
+					"suggestion": {"type": "string"}
+				}
+			}
+		}
+	}
+}`
+
+var findingsSchemaLoader = gojsonschema.NewStringLoader(findingsSchema)
+
+// parseFindings validates raw (the JSON text of an LLM completion) against
+// findingsSchema and decodes it into a slice of Finding.
+func parseFindings(raw string) ([]Finding, error) {
+	result, err := gojsonschema.Validate(findingsSchemaLoader, gojsonschema.NewStringLoader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("validate findings: %w", err)
+	}
+	if !result.Valid() {
+		var errs []string
+		for _, e := range result.Errors() {
+			errs = append(errs, e.String())
+		}
+		return nil, fmt.Errorf("findings did not match schema: %s", strings.Join(errs, "; "))
+	}
+
+	var decoded struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		return nil, fmt.Errorf("decode findings: %w", err)
+	}
+	return decoded.Findings, nil
+}
+
+// filterBySeverity returns the findings at or above minSeverity, preserving
+// order. An unrecognized or empty minSeverity returns findings unchanged.
+func filterBySeverity(findings []Finding, minSeverity string) []Finding {
+	rank, ok := severityRank[strings.ToLower(minSeverity)]
+	if !ok {
+		return findings
+	}
+
+	filtered := findings[:0:0]
+	for _, f := range findings {
+		if severityRank[strings.ToLower(f.Severity)] >= rank {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+var severityRank = map[string]int{
+	"low":      0,
+	"medium":   1,
+	"high":     2,
+	"critical": 3,
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestParseFindings(t *testing.T) {
+	raw := `{"findings":[{"rule":"fdic-disclosure","severity":"high","policy_excerpt":"must disclose FDIC insurance","webpage_excerpt":"no mention of FDIC","confidence":0.9,"suggestion":"add an FDIC disclosure"}]}`
+
+	findings, err := parseFindings(raw)
+	if err != nil {
+		t.Fatalf("parseFindings: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want 1", len(findings))
+	}
+	if findings[0].Rule != "fdic-disclosure" || findings[0].Severity != "high" {
+		t.Errorf("got %+v", findings[0])
+	}
+}
+
+func TestParseFindingsRejectsSchemaViolations(t *testing.T) {
+	cases := []string{
+		`{"findings":[{"rule":"x"}]}`,                                   // missing required fields
+		`{"findings":[{"rule":"x","severity":"urgent","policy_excerpt":"a","webpage_excerpt":"b","confidence":0.5,"suggestion":"c"}]}`, // bad enum
+		`not json`,
+	}
+	for _, raw := range cases {
+		if _, err := parseFindings(raw); err == nil {
+			t.Errorf("parseFindings(%q): expected error, got nil", raw)
+		}
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	findings := []Finding{
+		{Rule: "a", Severity: "low"},
+		{Rule: "b", Severity: "high"},
+		{Rule: "c", Severity: "critical"},
+	}
+
+	got := filterBySeverity(findings, "high")
+	if len(got) != 2 || got[0].Rule != "b" || got[1].Rule != "c" {
+		t.Errorf("got %+v", got)
+	}
+
+	if got := filterBySeverity(findings, ""); len(got) != 3 {
+		t.Errorf("empty minSeverity should return all findings, got %+v", got)
+	}
+
+	if got := filterBySeverity(findings, "bogus"); len(got) != 3 {
+		t.Errorf("unrecognized minSeverity should return all findings, got %+v", got)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// jobKeyPrefix namespaces job state within the Storage keyspace so List
+// can enumerate a owner's jobs without colliding with cached findings.
+const jobKeyPrefix = "jobs/"
+
+func jobKey(id string) string {
+	return jobKeyPrefix + id
+}
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job tracks a long-running comparison of one policy page against a growing
+// list of webpages. It is persisted as JSON in the owner's Storage so its
+// status survives across requests (PATCH can append more webpages and
+// resume it) for as long as the process stays up.
+type Job struct {
+	ID          string    `json:"id"`
+	Status      JobStatus `json:"status"`
+	StartedAt   time.Time `json:"started_at"`
+	Policy      string    `json:"policy"`
+	Webpages    []string  `json:"webpages"`
+	Mode        string    `json:"mode,omitempty"`  // "" (single page, default) or "crawl"; see FetchOptions
+	Depth       int       `json:"depth,omitempty"` // max sitemap crawl depth, used when Mode == "crawl"
+	Offset      int       `json:"offset"`          // index into Webpages already processed
+	ChunksDone  int       `json:"chunks_done"`
+	ChunksTotal int       `json:"chunks_total"`
+	Location    string    `json:"location"`
+	Findings    []Finding `json:"findings,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type jobTask struct {
+	owner string
+	jobID string
+}
+
+// startJobWorkers launches a bounded pool of background goroutines that
+// drain s.jobQueue, so POST/PATCH handlers never block on the OpenAI
+// round-trip.
+func (s *APIServer) startJobWorkers(n int) {
+	s.jobQueue = make(chan jobTask, 256)
+	for i := 0; i < n; i++ {
+		go func() {
+			for task := range s.jobQueue {
+				s.runJob(task.owner, task.jobID)
+			}
+		}()
+	}
+}
+
+func (s *APIServer) loadJob(owner, id string) (*Job, error) {
+	raw, err := s.store.Get(owner, jobKey(id))
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return nil, fmt.Errorf("decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (s *APIServer) saveJob(owner string, job *Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("encode job %s: %w", job.ID, err)
+	}
+	return s.store.Insert(owner, jobKey(job.ID), string(raw))
+}
+
+func (s *APIServer) failJob(owner string, job *Job, err error) {
+	job.Status = JobFailed
+	job.Error = err.Error()
+	s.saveJob(owner, job)
+}
+
+// estimateChunkPairs mirrors analyzeContent's chunking so job progress can
+// report a ChunksTotal before any completions have actually run.
+func (s *APIServer) estimateChunkPairs(policy, webpage []string) int {
+	chunkChars := tokensToChars(s.config.Analysis.ChunkTokens)
+	overlapChars := tokensToChars(s.config.Analysis.ChunkOverlapTokens)
+	policyChunks := splitIntoChunks(strings.Join(policy, "\n"), chunkChars, overlapChars)
+	webpageChunks := splitIntoChunks(strings.Join(webpage, "\n"), chunkChars, overlapChars)
+	return len(policyChunks) * len(webpageChunks)
+}
+
+// runJob processes every webpage from job.Offset onward, persisting
+// progress after each chunk pair and after each webpage so a concurrent
+// GET always sees up-to-date status.
+func (s *APIServer) runJob(owner, jobID string) {
+	job, err := s.loadJob(owner, jobID)
+	if err != nil {
+		return
+	}
+
+	job.Status = JobRunning
+	s.saveJob(owner, job)
+
+	ctx := context.Background()
+	fetchOpts := FetchOptions{Mode: job.Mode, Depth: job.Depth}
+
+	policyContent, err := fetchContent(ctx, job.Policy, fetchOpts)
+	if err != nil {
+		s.failJob(owner, job, fmt.Errorf("fetch policy: %w", err))
+		return
+	}
+
+	var mu sync.Mutex
+
+	for job.Offset < len(job.Webpages) {
+		webpage := job.Webpages[job.Offset]
+		webpageContent, err := fetchContent(ctx, webpage, fetchOpts)
+		if err != nil {
+			s.failJob(owner, job, fmt.Errorf("fetch webpage %q: %w", webpage, err))
+			return
+		}
+
+		job.ChunksTotal += s.estimateChunkPairs(policyContent, webpageContent)
+		s.saveJob(owner, job)
+
+		progress := func(done, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			job.ChunksDone++
+			s.saveJob(owner, job)
+		}
+
+		findings, err := s.analyzeContent(ctx, policyContent, webpageContent, progress)
+		if err != nil {
+			s.failJob(owner, job, fmt.Errorf("analyze %q: %w", webpage, err))
+			return
+		}
+
+		job.Findings = append(job.Findings, findings...)
+		job.Offset++
+		s.saveJob(owner, job)
+	}
+
+	job.Status = JobCompleted
+	s.saveJob(owner, job)
+}
+
+// createJob handles POST /compliance/jobs: it persists a pending Job,
+// enqueues it for background processing, and returns 202 Accepted with a
+// Location header pointing at the job's status endpoint.
+func (s *APIServer) createJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Policy   string   `json:"policy"`
+		Webpages []string `json:"webpages"`
+		Mode     string   `json:"mode,omitempty"`
+		Depth    int      `json:"depth,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Policy == "" || len(req.Webpages) == 0 {
+		http.Error(w, "policy and webpages are required", http.StatusBadRequest)
+		return
+	}
+	if req.Depth <= 0 {
+		req.Depth = 2
+	}
+
+	owner := ownerFromContext(r.Context())
+	id := uuid.NewString()
+	job := &Job{
+		ID:        id,
+		Status:    JobPending,
+		StartedAt: time.Now(),
+		Policy:    req.Policy,
+		Webpages:  req.Webpages,
+		Mode:      req.Mode,
+		Depth:     req.Depth,
+		Location:  fmt.Sprintf("/compliance/jobs/%s", id),
+	}
+	if err := s.saveJob(owner, job); err != nil {
+		http.Error(w, "failed to persist job", http.StatusInternalServerError)
+		return
+	}
+
+	s.jobQueue <- jobTask{owner: owner, jobID: id}
+
+	w.Header().Set("Location", job.Location)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// getJob handles GET /compliance/jobs/{id}, reporting current progress via
+// both the JSON body and a Range-style header.
+func (s *APIServer) getJob(w http.ResponseWriter, r *http.Request) {
+	owner := ownerFromContext(r.Context())
+	id := mux.Vars(r)["id"]
+
+	job, err := s.loadJob(owner, id)
+	if err != nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Range", fmt.Sprintf("chunks=0-%d/%d", job.ChunksDone, job.ChunksTotal))
+	json.NewEncoder(w).Encode(job)
+}
+
+// patchJob handles PATCH /compliance/jobs/{id}: it appends more webpages to
+// compare against the job's policy and re-enqueues it, resuming from
+// job.Offset if it had already finished or failed.
+func (s *APIServer) patchJob(w http.ResponseWriter, r *http.Request) {
+	owner := ownerFromContext(r.Context())
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		Webpages []string `json:"webpages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Webpages) == 0 {
+		http.Error(w, "webpages is required", http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.loadJob(owner, id)
+	if err != nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	// runJob holds its own in-memory snapshot for the lifetime of the run
+	// and blindly overwrites it on every save, so a second PATCH while one
+	// is already in flight would race it and lose progress. Reject instead
+	// of enqueueing a second worker for the same job.
+	if job.Status == JobRunning {
+		http.Error(w, "job is already running, retry once it completes", http.StatusConflict)
+		return
+	}
+
+	job.Webpages = append(job.Webpages, req.Webpages...)
+	if job.Status == JobCompleted || job.Status == JobFailed {
+		job.Status = JobPending
+		job.Error = ""
+	}
+	if err := s.saveJob(owner, job); err != nil {
+		http.Error(w, "failed to persist job", http.StatusInternalServerError)
+		return
+	}
+
+	s.jobQueue <- jobTask{owner: owner, jobID: id}
+
+	w.Header().Set("Location", job.Location)
+	json.NewEncoder(w).Encode(job)
+}
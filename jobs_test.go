@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sashabaranov/go-openai"
+)
+
+func newTestServerWithAuth(t *testing.T) (*APIServer, string) {
+	t.Helper()
+	storage := NewMemoryStorage()
+	config := &Config{Port: 8080}
+	config.Analysis.ChunkTokens = 500
+	config.Analysis.Concurrency = 2
+	config.Jobs.Concurrency = 1
+	openAiClient := openai.NewClient(os.Getenv("OPENAPI_KEY"))
+	listenAddr := fmt.Sprintf(":%d", config.Port)
+	server := NewServer(listenAddr, storage, openAiClient, config)
+	return server, "test-token"
+}
+
+func withOwner(req *http.Request, owner string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), ownerContextKey, owner))
+}
+
+func TestCreateJobReturnsAcceptedWithLocation(t *testing.T) {
+	server, owner := newTestServerWithAuth(t)
+
+	policySite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>All deposits are FDIC insured.</p></body></html>`))
+	}))
+	t.Cleanup(policySite.Close)
+	webpageSite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>Open a bank account with us today.</p></body></html>`))
+	}))
+	t.Cleanup(webpageSite.Close)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"policy":   policySite.URL,
+		"webpages": []string{webpageSite.URL},
+	})
+	req := httptest.NewRequest("POST", "/compliance/jobs", bytes.NewReader(body))
+	req = withOwner(req, owner)
+	rr := httptest.NewRecorder()
+
+	server.createJob(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusAccepted)
+	}
+	location := rr.Header().Get("Location")
+	if location == "" {
+		t.Fatal("expected a Location header")
+	}
+
+	var job Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if job.Status != JobPending && job.Status != JobRunning {
+		t.Errorf("got status %q, want pending or running", job.Status)
+	}
+}
+
+func TestGetJobNotFound(t *testing.T) {
+	server, owner := newTestServerWithAuth(t)
+
+	req := httptest.NewRequest("GET", "/compliance/jobs/does-not-exist", nil)
+	req = withOwner(req, owner)
+	req = mux.SetURLVars(req, map[string]string{"id": "does-not-exist"})
+	rr := httptest.NewRecorder()
+
+	server.getJob(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestPatchJobAppendsWebpages(t *testing.T) {
+	server, owner := newTestServerWithAuth(t)
+
+	policySite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>All deposits are FDIC insured.</p></body></html>`))
+	}))
+	t.Cleanup(policySite.Close)
+	webpageSiteA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>page a</p></body></html>`))
+	}))
+	t.Cleanup(webpageSiteA.Close)
+	webpageSiteB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>page b</p></body></html>`))
+	}))
+	t.Cleanup(webpageSiteB.Close)
+
+	job := &Job{ID: "job-1", Status: JobCompleted, Policy: policySite.URL, Webpages: []string{webpageSiteA.URL}, Location: "/compliance/jobs/job-1"}
+	if err := server.saveJob(owner, job); err != nil {
+		t.Fatalf("saveJob: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"webpages": []string{webpageSiteB.URL}})
+	req := httptest.NewRequest("PATCH", "/compliance/jobs/job-1", bytes.NewReader(body))
+	req = withOwner(req, owner)
+	req = mux.SetURLVars(req, map[string]string{"id": "job-1"})
+	rr := httptest.NewRecorder()
+
+	server.patchJob(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	updated, err := server.loadJob(owner, "job-1")
+	if err != nil {
+		t.Fatalf("loadJob: %v", err)
+	}
+	if len(updated.Webpages) != 2 {
+		t.Fatalf("got %d webpages, want 2", len(updated.Webpages))
+	}
+	if updated.Status != JobPending {
+		t.Errorf("got status %q, want %q after resuming a completed job", updated.Status, JobPending)
+	}
+}
@@ -1,43 +1,44 @@
 package main
 
 import (
-	"context"
 	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
-	"sync"
 
 	"encoding/json"
 	"github.com/spf13/viper"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/gorilla/mux"
 	"github.com/sashabaranov/go-openai"
 )
 
-type Storage interface {
-	Insert(key string, value string) error
-	Get(key string) (string, error)
-}
-
-type InMemory struct {
-	db   map[string]string
-	lock sync.RWMutex
-}
-
 type Config struct {
 	OpenAI struct {
 		Model  string `mapstructure:"model"`
 		Tokens int    `mapstructure:"tokens"`
 	} `mapstructure:"openai"`
-	Port int `mapstructure:"port"`
+	Port   int    `mapstructure:"port"`
 	Prompt string `mapstructure:"prompt"`
+	Storage struct {
+		Driver string `mapstructure:"driver"` // "memory" (default), "sqlite", or "postgres"
+		DSN    string `mapstructure:"dsn"`
+	} `mapstructure:"storage"`
+	Analysis struct {
+		ChunkTokens        int    `mapstructure:"chunk_tokens"`         // max tokens per chunk, approximated as chars/4
+		ChunkOverlapTokens int    `mapstructure:"chunk_overlap_tokens"` // tokens of overlap between consecutive chunks
+		Concurrency        int    `mapstructure:"concurrency"`          // max in-flight chunk-vs-chunk completions
+		ReducePrompt       string `mapstructure:"reduce_prompt"`        // %s: newline-separated partial findings
+	} `mapstructure:"analysis"`
+	Jobs struct {
+		Concurrency int `mapstructure:"concurrency"` // background workers processing /compliance/jobs
+	} `mapstructure:"jobs"`
+	Rules struct {
+		Path string `mapstructure:"path"` // YAML file of deterministic RulesEngine rules; empty disables it
+	} `mapstructure:"rules"`
 }
 
 func ReadConfig() (*Config, error) {
@@ -62,52 +63,55 @@ func ReadConfig() (*Config, error) {
 
 }
 
-func NewMemoryStorage() *InMemory {
-	return &InMemory{
-		db:   make(map[string]string),
-		lock: sync.RWMutex{},
-	}
-}
-
-func (i *InMemory) Insert(key string, value string) error {
-	i.lock.Lock()
-	defer i.lock.Unlock()
-	if i.db == nil {
-		i.db = make(map[string]string)
-	}
-	i.db[key] = value
-	return nil
-}
-
-func (i *InMemory) Get(key string) (string, error) {
-	i.lock.RLock()
-	defer i.lock.RUnlock()
-	if val, ok := i.db[key]; ok {
-		return val, nil
-	}
-	return "", fmt.Errorf("key not found")
-}
-
 type APIServer struct {
-	listenAddr string
-	store      Storage
-	aiClient   *openai.Client
-	config     *Config
+	listenAddr  string
+	store       Storage
+	aiClient    *openai.Client
+	config      *Config
+	jobQueue    chan jobTask
+	rulesEngine *RulesEngine
 }
 
-func NewAPIServer(listenAddr string, store Storage, openaiClient *openai.Client, config *Config) *APIServer {
-	return &APIServer{
+// NewServer wires an APIServer around the given Storage backend. Every
+// request to /compliance is authenticated against store via Bearer token,
+// so callers only ever see their own cached findings. It also starts the
+// background worker pool that processes /compliance/jobs, and, if
+// config.Rules.Path is set, loads the deterministic RulesEngine that runs
+// alongside the LLM.
+func NewServer(listenAddr string, store Storage, openaiClient *openai.Client, config *Config) *APIServer {
+	s := &APIServer{
 		listenAddr: listenAddr,
 		store:      store,
 		aiClient:   openaiClient,
 		config:     config,
 	}
+
+	if config.Rules.Path != "" {
+		engine, err := LoadRulesEngine(config.Rules.Path)
+		if err != nil {
+			log.Printf("rules engine disabled: %v", err)
+		} else {
+			s.rulesEngine = engine
+		}
+	}
+
+	workers := config.Jobs.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	s.startJobWorkers(workers)
+
+	return s
 }
 
 func (s *APIServer) Run() {
 	router := mux.NewRouter()
 	router.Use(Logger)
-	router.HandleFunc("/compliance", s.analyze).Methods("POST")
+	router.Handle("/compliance", s.authMiddleware(http.HandlerFunc(s.analyze))).Methods("POST")
+	router.Handle("/compliance/stream", s.authMiddleware(http.HandlerFunc(s.analyzeStream))).Methods("GET")
+	router.Handle("/compliance/jobs", s.authMiddleware(http.HandlerFunc(s.createJob))).Methods("POST")
+	router.Handle("/compliance/jobs/{id}", s.authMiddleware(http.HandlerFunc(s.getJob))).Methods("GET")
+	router.Handle("/compliance/jobs/{id}", s.authMiddleware(http.HandlerFunc(s.patchJob))).Methods("PATCH")
 	fmt.Println("API server running on port: ", s.listenAddr)
 	http.ListenAndServe(s.listenAddr, router)
 }
@@ -116,48 +120,61 @@ func createCacheKey(policy string, webpage string) string {
 	return policy + webpage
 }
 
+// complianceResponse is the JSON shape returned by /compliance: llm_findings
+// come from the map-reduce model analysis, rule_findings from the
+// deterministic RulesEngine (empty if none is configured), and both are
+// narrowed by the optional ?severity= query param.
+type complianceResponse struct {
+	LLMFindings  []Finding `json:"llm_findings"`
+	RuleFindings []Finding `json:"rule_findings"`
+}
+
 func (s *APIServer) analyze(w http.ResponseWriter, r *http.Request) {
 	policy := r.URL.Query().Get("policy")
 	webpage := r.URL.Query().Get("webpage")
+	severity := r.URL.Query().Get("severity")
 
 	if policy == "" || webpage == "" {
 		http.Error(w, "Invalid input: policy is invalid", http.StatusBadRequest)
 		return
 	}
 
+	owner := ownerFromContext(r.Context())
+
 	// Create a key from the policy and webpage URLs
 	key := createCacheKey(policy, webpage)
 
+	fetchOpts := FetchOptionsFromRequest(r)
+	webpageContent, err := fetchContent(r.Context(), webpage, fetchOpts)
+	if err != nil {
+		http.Error(w, "Invalid webpage URL", http.StatusBadRequest)
+		return
+	}
+	var ruleFindings []Finding
+	if s.rulesEngine != nil {
+		ruleFindings = s.rulesEngine.Evaluate(webpageContent)
+	}
+
 	// Check if the key exists in the storage
-	filePath, err := s.store.Get(key)
+	filePath, err := s.store.Get(owner, key)
 	if err == nil {
-		// If the key exists, read the content from the file at the stored file path
-		contentBytes, err := ioutil.ReadFile(filePath) // Assuming filePath is a list of strings and we need the first path
+		// If the key exists, read the cached llm_findings from the stored file path
+		contentBytes, err := ioutil.ReadFile(filePath)
 		if err == nil {
-			content := strings.Split(string(contentBytes), "\n") // Parse content as list of strings
-			response := map[string]string{
-				"Response": strings.Join(content, "\n"),
-			}
-			if err := json.NewEncoder(w).Encode(response); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			var llmFindings []Finding
+			if err := json.Unmarshal(contentBytes, &llmFindings); err == nil {
+				s.writeComplianceResponse(w, llmFindings, ruleFindings, severity)
 				return
 			}
-			// w.Write([]byte(strings.Join(content, "\n")))         // Save and return the content
-			return
 		}
 	}
 
-	policyContent, err := fetchContent(policy)
+	policyContent, err := fetchContent(r.Context(), policy, fetchOpts)
 	if err != nil {
 		http.Error(w, "Invalid policy URL", http.StatusBadRequest)
 		return
 	}
-	webpageContent, err := fetchContent(webpage)
-	if err != nil {
-		http.Error(w, "Invalid webpage URL", http.StatusBadRequest)
-		return
-	}
-	findings, err := s.analyzeContent(policyContent, webpageContent)
+	llmFindings, err := s.analyzeContent(r.Context(), policyContent, webpageContent, nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -168,31 +185,26 @@ func (s *APIServer) analyze(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to create directory for findings", http.StatusInternalServerError)
 		return
 	}
-	// Create the file only if it doesn't exist
-	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	encoded, err := json.Marshal(llmFindings)
 	if err != nil {
-		if os.IsExist(err) {
-			f, err = os.OpenFile(file, os.O_WRONLY, 0644)
-			if err != nil {
-				http.Error(w, "Failed to open existing file", http.StatusInternalServerError)
-				return
-			}
-			// File already exists, no need to create it
-		} else {
-			// Some other error occurred
-			http.Error(w, "Failed to create file", http.StatusInternalServerError)
-			return
-		}
+		http.Error(w, "Failed to encode findings", http.StatusInternalServerError)
+		return
 	}
-	defer f.Close()
-	if _, err := f.Write([]byte(strings.Join(findings, " "))); err != nil {
+	if err := os.WriteFile(file, encoded, 0644); err != nil {
 		http.Error(w, "Failed to save findings", http.StatusInternalServerError)
 		return
 	}
 
-	s.store.Insert(key, file)
-	response := map[string]string{
-		"Response": strings.Join(findings, " "),
+	s.store.Insert(owner, key, file)
+	s.writeComplianceResponse(w, llmFindings, ruleFindings, severity)
+}
+
+// writeComplianceResponse applies the optional severity filter to both
+// finding sets and writes the JSON compliance response.
+func (s *APIServer) writeComplianceResponse(w http.ResponseWriter, llmFindings, ruleFindings []Finding, severity string) {
+	response := complianceResponse{
+		LLMFindings:  filterBySeverity(llmFindings, severity),
+		RuleFindings: filterBySeverity(ruleFindings, severity),
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -200,146 +212,52 @@ func (s *APIServer) analyze(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func splitIntoChunks(s string, chunkSize int) []string {
-	var chunks []string
-	for len(s) > chunkSize {
-		chunks = append(chunks, s[:chunkSize])
-		s = s[chunkSize:]
-	}
-	chunks = append(chunks, s)
-
-	return chunks
+func Logger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println("Before request processing")
+		next.ServeHTTP(w, r)
+		fmt.Println("After request processing")
+	})
 }
 
-func shouldRetry(err error) bool {
-	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-		if httpErr, ok := err.(*openai.APIError); ok {
-			if httpErr.HTTPStatusCode == http.StatusInternalServerError ||
-				httpErr.HTTPStatusCode == http.StatusBadGateway ||
-				httpErr.HTTPStatusCode == http.StatusServiceUnavailable ||
-				httpErr.HTTPStatusCode == http.StatusGatewayTimeout {
-				return true
-			}
-		}
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		runInit()
+		return
 	}
 
-	return false
-}
-
-func (s *APIServer) analyzeContent(policy, webpage []string) ([]string, error) {
-	return s.analyzeContentWithRetry(policy, webpage, 3)
-}
-
-func (s *APIServer) analyzeContentWithRetry(policy, webpage []string, retryCount int) ([]string, error) {
-	// Initialize the OpenAI client
-	client := s.aiClient
-	prompt := fmt.Sprintf(s.config.Prompt, webpage, policy)
-
-	dialogue := []openai.ChatCompletionMessage{
-		{Role: openai.ChatMessageRoleUser, Content: prompt},
-	}
-	tokens := s.config.OpenAI.Tokens
-
-	// Send the prompt to the GPT-4 model
-	// TODO: prompt messgae can be too big due to page content so I think we should create chunk of target page and and check with each chunk of policy else for pig pages it can fails
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model:     openai.GPT4,
-			MaxTokens: tokens,
-			Messages:  dialogue,
-
-		})
+	config, _ := ReadConfig()
+	storage, err := NewStorageFromConfig(config)
 	if err != nil {
-		if shouldRetry(err) && retryCount > 0 {
-			return s.analyzeContentWithRetry(policy, webpage, retryCount-1)
-		}
-		return nil, err
+		log.Fatalf("failed to initialize storage: %v", err)
 	}
-
-	// Extract the findings from the model's output
-	findings := strings.Fields(resp.Choices[0].Message.Content)
-
-	return findings, nil
+	openAiClient := openai.NewClient(os.Getenv("OPENAPI_KEY"))
+	listenAddr := fmt.Sprintf(":%d", config.Port)
+	server := NewServer(listenAddr, storage, openAiClient, config)
+	server.Run()
 }
 
-func fetchContent(url string) ([]string, error) {
-	// Fetch the URL
-	resp, err := http.Get(url)
-	if err != nil {
-		return []string{}, nil
+// runInit bootstraps the SQL schema (sqlite/postgres, per config.yaml) and
+// mints an initial admin API token.
+func runInit() {
+	config, _ := ReadConfig()
+	if config.Storage.Driver != "sqlite" && config.Storage.Driver != "postgres" {
+		log.Fatalf("init requires storage.driver to be \"sqlite\" or \"postgres\", got %q", config.Storage.Driver)
 	}
-	defer resp.Body.Close()
 
-	// Parse the page body
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	store, err := NewSQLStorage(config.Storage.Driver, config.Storage.DSN)
 	if err != nil {
-		return []string{}, nil
+		log.Fatalf("failed to open storage: %v", err)
 	}
+	defer store.Close()
 
-	// Use maps to track unique entries
-	uniqueHeadings := make(map[string]bool)
-	uniqueParagraphs := make(map[string]bool)
-	uniqueLists := make(map[string]bool)
-	uniqueTables := make(map[string]bool)
-
-	var output []string
-
-	// Extract all elements in the order they appear
-	doc.Find("*").Each(func(i int, s *goquery.Selection) {
-		switch goquery.NodeName(s) {
-		case "h2", "h3":
-			headingText := strings.TrimSpace(s.Text())
-			if _, exists := uniqueHeadings[headingText]; !exists {
-				output = append(output, headingText)
-				uniqueHeadings[headingText] = true
-			}
-		case "p":
-			paragraphText := strings.TrimSpace(s.Text())
-			if _, exists := uniqueParagraphs[paragraphText]; !exists {
-				output = append(output, paragraphText)
-				uniqueParagraphs[paragraphText] = true
-			}
-		case "ul":
-			listItems := s.Find("li").Map(func(i int, s *goquery.Selection) string {
-				return strings.TrimSpace(s.Text())
-			})
-			listText := strings.Join(listItems, "\n")
-			if _, exists := uniqueLists[listText]; !exists {
-				output = append(output, listText)
-				uniqueLists[listText] = true
-			}
-		case "table":
-			tableRows := s.Find("tr").Map(func(i int, s *goquery.Selection) string {
-				columns := s.Find("th, td").Map(func(i int, s *goquery.Selection) string {
-					return strings.TrimSpace(s.Text())
-				})
-				return strings.Join(columns, "\t")
-			})
-			tableText := strings.Join(tableRows, "\n")
-			if _, exists := uniqueTables[tableText]; !exists {
-				output = append(output, tableText)
-				uniqueTables[tableText] = true
-			}
-		}
-	})
-
-	return output, nil
-}
-
-func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Before request processing")
-		next.ServeHTTP(w, r)
-		fmt.Println("After request processing")
-	})
-}
+	if err := store.Migrate(); err != nil {
+		log.Fatalf("failed to migrate schema: %v", err)
+	}
 
-func main() {
-	storage := NewMemoryStorage()
-	config, _ := ReadConfig()
-	openAiClient := openai.NewClient(os.Getenv("OPENAPI_KEY"))
-	listenAddr := fmt.Sprintf(":%d", config.Port)
-	server := NewAPIServer(listenAddr, storage, openAiClient, config)
-	server.Run()
+	token, err := store.CreateUser("admin")
+	if err != nil {
+		log.Fatalf("failed to create admin token: %v", err)
+	}
+	fmt.Printf("schema initialized, admin token: %s\n", token)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/sashabaranov/go-openai"
 	"net/http"
@@ -21,17 +22,23 @@ func TestAnalyze(t *testing.T) {
 		t.Fatal(err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer test-token")
 
 	// Create a ResponseRecorder to record the response.
 	rr := httptest.NewRecorder()
 	// Create a new APIServer instance for testing.
 	storage := NewMemoryStorage()
-	config, _ := ReadConfig()
+	config := &Config{Port: 8080}
+	config.Analysis.ChunkTokens = 500
+	config.Analysis.Concurrency = 2
+	config.Jobs.Concurrency = 1
 	openAiClient := openai.NewClient(os.Getenv("OPENAPI_KEY"))
 	listenAddr := fmt.Sprintf(":%d", config.Port)
-	server := NewAPIServer(listenAddr, storage, openAiClient, config)
+	server := NewServer(listenAddr, storage, openAiClient, config)
 
-	// Call the analyze function directly.
+	// Call the analyze function directly (bypassing the auth middleware,
+	// so stash the owner in the context the same way it would).
+	req = req.WithContext(context.WithValue(req.Context(), ownerContextKey, "test-token"))
 	server.analyze(rr, req)
 
 	fmt.Println("", rr.Body.String())
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is one deterministic compliance check: Trigger is a regex/keyword
+// that, if found in the webpage content, requires Required to also be
+// present nearby (e.g. "bank account" requires an FDIC disclosure). A
+// missing Required is reported as a Finding.
+type Rule struct {
+	Name       string `yaml:"name"`
+	Severity   string `yaml:"severity"`
+	Trigger    string `yaml:"trigger"`
+	Required   string `yaml:"required,omitempty"`
+	Suggestion string `yaml:"suggestion"`
+
+	trigger  *regexp.Regexp
+	required *regexp.Regexp
+}
+
+// RulesEngine evaluates a fixed set of Rules against webpage content,
+// independently of and alongside the LLM.
+type RulesEngine struct {
+	rules []Rule
+}
+
+// LoadRulesEngine reads and compiles a YAML rules file such as:
+//
+//	rules:
+//	  - name: fdic-disclosure-near-bank-account
+//	    severity: high
+//	    trigger: (?i)bank account
+//	    required: (?i)FDIC insured
+//	    suggestion: Add an FDIC disclosure near any mention of "bank account".
+func LoadRulesEngine(path string) (*RulesEngine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file %s: %w", path, err)
+	}
+
+	var doc struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse rules file %s: %w", path, err)
+	}
+
+	for i := range doc.Rules {
+		rule := &doc.Rules[i]
+		rule.trigger, err = regexp.Compile(rule.Trigger)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: invalid trigger regex: %w", rule.Name, err)
+		}
+		if rule.Required != "" {
+			rule.required, err = regexp.Compile(rule.Required)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid required regex: %w", rule.Name, err)
+			}
+		}
+	}
+
+	return &RulesEngine{rules: doc.Rules}, nil
+}
+
+// Evaluate runs every rule against webpage content, reporting a Finding for
+// each triggered rule whose Required pattern is absent.
+func (e *RulesEngine) Evaluate(webpage []string) []Finding {
+	content := strings.Join(webpage, "\n")
+
+	var findings []Finding
+	for _, rule := range e.rules {
+		loc := rule.trigger.FindStringIndex(content)
+		if loc == nil {
+			continue
+		}
+		if rule.required != nil && rule.required.MatchString(content) {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Rule:           rule.Name,
+			Severity:       rule.Severity,
+			WebpageExcerpt: excerpt(content, loc[0], loc[1]),
+			Confidence:     1,
+			Suggestion:     rule.Suggestion,
+		})
+	}
+	return findings
+}
+
+// excerpt returns content around [start, end), trimmed, for use as a
+// finding's WebpageExcerpt.
+func excerpt(content string, start, end int) string {
+	const padding = 60
+	lo := start - padding
+	if lo < 0 {
+		lo = 0
+	}
+	hi := end + padding
+	if hi > len(content) {
+		hi = len(content)
+	}
+	return strings.TrimSpace(content[lo:hi])
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRulesFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("write rules file: %v", err)
+	}
+	return path
+}
+
+func TestLoadRulesEngineEvaluatesTriggerAndRequired(t *testing.T) {
+	path := writeRulesFile(t, `
+rules:
+  - name: fdic-disclosure-near-bank-account
+    severity: high
+    trigger: (?i)bank account
+    required: (?i)FDIC insured
+    suggestion: Add an FDIC disclosure near any mention of "bank account".
+`)
+
+	engine, err := LoadRulesEngine(path)
+	if err != nil {
+		t.Fatalf("LoadRulesEngine: %v", err)
+	}
+
+	findings := engine.Evaluate([]string{"Open a bank account with us today."})
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want 1: %+v", len(findings), findings)
+	}
+	if findings[0].Rule != "fdic-disclosure-near-bank-account" || findings[0].Severity != "high" {
+		t.Errorf("got %+v", findings[0])
+	}
+
+	// When the required disclosure is present, no finding should be raised.
+	clean := engine.Evaluate([]string{"Open a bank account with us today. All deposits are FDIC insured."})
+	if len(clean) != 0 {
+		t.Errorf("got %d findings, want 0: %+v", len(clean), clean)
+	}
+}
+
+func TestLoadRulesEngineInvalidRegex(t *testing.T) {
+	path := writeRulesFile(t, `
+rules:
+  - name: broken
+    severity: low
+    trigger: "(["
+`)
+
+	if _, err := LoadRulesEngine(path); err == nil {
+		t.Fatal("expected an error for an invalid trigger regex")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Meta describes a cached entry without loading its value.
+type Meta struct {
+	Owner     string
+	Key       string
+	CreatedAt time.Time
+}
+
+// Storage persists cached compliance findings and authenticates API tokens.
+// Every read/write is scoped to an owner so that tokens cannot see each
+// other's cached results.
+type Storage interface {
+	Insert(owner, key, value string) error
+	Get(owner, key string) (string, error)
+	Delete(owner, key string) error
+	List(owner, prefix string) ([]string, error)
+	GetMeta(owner, key string) (*Meta, error)
+
+	// Authenticate resolves a bearer token to the owner it belongs to.
+	Authenticate(token string) (owner string, err error)
+}
+
+type InMemory struct {
+	db   map[string]string
+	meta map[string]*Meta
+	lock sync.RWMutex
+}
+
+func NewMemoryStorage() *InMemory {
+	return &InMemory{
+		db:   make(map[string]string),
+		meta: make(map[string]*Meta),
+		lock: sync.RWMutex{},
+	}
+}
+
+// scopedKey namespaces a key under its owner so two tokens never collide.
+func scopedKey(owner, key string) string {
+	return owner + "\x00" + key
+}
+
+func (i *InMemory) Insert(owner, key, value string) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.db == nil {
+		i.db = make(map[string]string)
+	}
+	scoped := scopedKey(owner, key)
+	i.db[scoped] = value
+	i.meta[scoped] = &Meta{Owner: owner, Key: key, CreatedAt: time.Now()}
+	return nil
+}
+
+func (i *InMemory) Get(owner, key string) (string, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if val, ok := i.db[scopedKey(owner, key)]; ok {
+		return val, nil
+	}
+	return "", fmt.Errorf("key not found")
+}
+
+func (i *InMemory) Delete(owner, key string) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	scoped := scopedKey(owner, key)
+	delete(i.db, scoped)
+	delete(i.meta, scoped)
+	return nil
+}
+
+func (i *InMemory) List(owner, prefix string) ([]string, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	ownerNS := scopedKey(owner, "")
+	var keys []string
+	for scoped := range i.db {
+		if !strings.HasPrefix(scoped, ownerNS) {
+			continue
+		}
+		key := strings.TrimPrefix(scoped, ownerNS)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (i *InMemory) GetMeta(owner, key string) (*Meta, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if m, ok := i.meta[scopedKey(owner, key)]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("key not found")
+}
+
+// Authenticate is a no-op for the in-memory backend: since nothing survives
+// a restart anyway, every token is treated as its own owner namespace.
+func (i *InMemory) Authenticate(token string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("missing token")
+	}
+	return token, nil
+}
+
+// NewStorageFromConfig builds the Storage backend configured in config.yaml,
+// falling back to the in-memory store when none is configured.
+func NewStorageFromConfig(config *Config) (Storage, error) {
+	switch config.Storage.Driver {
+	case "", "memory":
+		return NewMemoryStorage(), nil
+	case "sqlite", "postgres":
+		store, err := NewSQLStorage(config.Storage.Driver, config.Storage.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("open %s storage: %w", config.Storage.Driver, err)
+		}
+		if err := store.Migrate(); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("migrate %s storage: %w", config.Storage.Driver, err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", config.Storage.Driver)
+	}
+}
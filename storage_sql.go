@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlSchema creates the findings and tokens tables. It is written to run
+// against both SQLite and Postgres; driver-specific quirks (autoincrement,
+// upsert syntax) are handled per-statement below rather than here.
+const sqlSchema = `
+CREATE TABLE IF NOT EXISTS findings (
+	owner      TEXT NOT NULL,
+	key        TEXT NOT NULL,
+	value      TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (owner, key)
+);
+
+CREATE TABLE IF NOT EXISTS tokens (
+	token      TEXT PRIMARY KEY,
+	owner      TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+`
+
+// SQLStorage is a Storage backed by database/sql, persisting both cached
+// findings and per-user API tokens so a restart doesn't lose either.
+type SQLStorage struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewSQLStorage opens (but does not migrate) a SQL-backed Storage. driver is
+// "sqlite" or "postgres"; dsn is passed straight to database/sql.
+func NewSQLStorage(driver, dsn string) (*SQLStorage, error) {
+	sqlDriver := driver
+	if driver == "sqlite" {
+		sqlDriver = "sqlite3"
+	}
+	db, err := sql.Open(sqlDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", sqlDriver, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping %s: %w", sqlDriver, err)
+	}
+	return &SQLStorage{db: db, driver: driver}, nil
+}
+
+// Migrate creates the schema if it does not already exist. Safe to call on
+// every startup.
+func (s *SQLStorage) Migrate() error {
+	_, err := s.db.Exec(sqlSchema)
+	return err
+}
+
+func (s *SQLStorage) Close() error {
+	return s.db.Close()
+}
+
+// placeholders rewrites "?" placeholders to "$1", "$2", ... for Postgres.
+func (s *SQLStorage) placeholders(query string) string {
+	if s.driver != "postgres" {
+		return query
+	}
+	n := 0
+	out := make([]byte, 0, len(query)+8)
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			out = append(out, []byte(fmt.Sprintf("$%d", n))...)
+			continue
+		}
+		out = append(out, query[i])
+	}
+	return string(out)
+}
+
+func (s *SQLStorage) Insert(owner, key, value string) error {
+	query := s.placeholders(`
+		INSERT INTO findings (owner, key, value, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (owner, key) DO UPDATE SET value = excluded.value, created_at = CURRENT_TIMESTAMP
+	`)
+	_, err := s.db.Exec(query, owner, key, value)
+	return err
+}
+
+func (s *SQLStorage) Get(owner, key string) (string, error) {
+	query := s.placeholders(`SELECT value FROM findings WHERE owner = ? AND key = ?`)
+	var value string
+	err := s.db.QueryRow(query, owner, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("key not found")
+	}
+	return value, err
+}
+
+func (s *SQLStorage) Delete(owner, key string) error {
+	query := s.placeholders(`DELETE FROM findings WHERE owner = ? AND key = ?`)
+	_, err := s.db.Exec(query, owner, key)
+	return err
+}
+
+func (s *SQLStorage) List(owner, prefix string) ([]string, error) {
+	query := s.placeholders(`SELECT key FROM findings WHERE owner = ? AND key LIKE ? ORDER BY key`)
+	rows, err := s.db.Query(query, owner, prefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLStorage) GetMeta(owner, key string) (*Meta, error) {
+	query := s.placeholders(`SELECT created_at FROM findings WHERE owner = ? AND key = ?`)
+	meta := &Meta{Owner: owner, Key: key}
+	err := s.db.QueryRow(query, owner, key).Scan(&meta.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("key not found")
+	}
+	return meta, err
+}
+
+func (s *SQLStorage) Authenticate(token string) (string, error) {
+	query := s.placeholders(`SELECT owner FROM tokens WHERE token = ?`)
+	var owner string
+	err := s.db.QueryRow(query, token).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("invalid token")
+	}
+	return owner, err
+}
+
+// CreateUser mints a new random API token for owner and persists it.
+func (s *SQLStorage) CreateUser(owner string) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	query := s.placeholders(`INSERT INTO tokens (token, owner, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)`)
+	if _, err := s.db.Exec(query, token, owner); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
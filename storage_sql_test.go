@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestSQLStorage(t *testing.T) *SQLStorage {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewSQLStorage("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLStorage: %v", err)
+	}
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLStorageSurvivesRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := NewSQLStorage("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLStorage: %v", err)
+	}
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err := store.Insert("owner-a", "policy|webpage", "findings-a"); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	store.Close()
+
+	reopened, err := NewSQLStorage("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLStorage (reopen): %v", err)
+	}
+	defer reopened.Close()
+
+	value, err := reopened.Get("owner-a", "policy|webpage")
+	if err != nil {
+		t.Fatalf("Get after reopen: %v", err)
+	}
+	if value != "findings-a" {
+		t.Errorf("got %q, want %q", value, "findings-a")
+	}
+}
+
+func TestSQLStorageScopesByOwner(t *testing.T) {
+	store := newTestSQLStorage(t)
+
+	if err := store.Insert("owner-a", "key", "a-findings"); err != nil {
+		t.Fatalf("Insert owner-a: %v", err)
+	}
+	if err := store.Insert("owner-b", "key", "b-findings"); err != nil {
+		t.Fatalf("Insert owner-b: %v", err)
+	}
+
+	if _, err := store.Get("owner-c", "key"); err == nil {
+		t.Error("expected error for unknown owner, got nil")
+	}
+
+	value, err := store.Get("owner-a", "key")
+	if err != nil {
+		t.Fatalf("Get owner-a: %v", err)
+	}
+	if value != "a-findings" {
+		t.Errorf("got %q, want %q", value, "a-findings")
+	}
+
+	if err := store.Delete("owner-a", "key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get("owner-a", "key"); err == nil {
+		t.Error("expected error after delete, got nil")
+	}
+}
+
+func TestSQLStorageTokens(t *testing.T) {
+	store := newTestSQLStorage(t)
+
+	token, err := store.CreateUser("alice")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	owner, err := store.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if owner != "alice" {
+		t.Errorf("got owner %q, want %q", owner, "alice")
+	}
+
+	if _, err := store.Authenticate("not-a-real-token"); err == nil {
+		t.Error("expected error for unknown token, got nil")
+	}
+}